@@ -19,24 +19,59 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"hash"
+	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/ghodss/yaml"
 	"github.com/golang/glog"
-	"github.com/google/go-github/github"
-	"golang.org/x/oauth2"
+
+	"github.com/spotinst/kopeio-shipbot/internal/notes"
 )
 
+// knownGOOS and knownGOARCH are used to recognize {os} and {arch} template
+// variables in the filenames matched by a glob/directory AssetMapping.
+var knownGOOS = map[string]bool{
+	"darwin":  true,
+	"linux":   true,
+	"windows": true,
+	"freebsd": true,
+	"openbsd": true,
+	"netbsd":  true,
+}
+
+var knownGOARCH = map[string]bool{
+	"386":     true,
+	"amd64":   true,
+	"arm":     true,
+	"arm64":   true,
+	"ppc64":   true,
+	"ppc64le": true,
+	"s390x":   true,
+}
+
 var (
 	tag               = ""
 	target            = ""
 	configFile        = ""
+	force             = false
+	prune             = false
+	publish           = false
+	dryRun            = false
+	baseURL           = ""
+	uploadURL         = ""
 	githubUser        = os.Getenv("GITHUB_USER")
 	githubPassword    = os.Getenv("GITHUB_PASSWORD")
 	githubAccessToken = os.Getenv("GITHUB_TOKEN")
@@ -46,19 +81,362 @@ type Config struct {
 	Owner string `json:"owner"`
 	Repo  string `json:"repo"`
 
+	// Provider selects the git forge to publish to: "github" (the default),
+	// "gitea" or "gitlab". "github" also covers GitHub Enterprise, selected
+	// by passing -base-url.
+	Provider string `json:"provider"`
+
 	Assets []AssetMapping `json:"assets"`
+
+	// Checksums, if set, causes shipbot to generate and upload an aggregated
+	// checksums manifest covering every synced asset.
+	Checksums *ChecksumsConfig `json:"checksums"`
+
+	// Sign, if set, causes shipbot to GPG-sign uploaded assets and/or the
+	// checksums manifest and upload the resulting signatures alongside them.
+	Sign *SignConfig `json:"sign"`
+
+	// Notes, if set, causes shipbot to generate release notes from the
+	// commit log and populate the release body of newly created releases.
+	Notes *NotesConfig `json:"notes"`
+
+	// Publish, if set, controls transitioning a release from draft to
+	// published, either immediately (Enabled, or the -publish flag) or via
+	// the separate "promote" subcommand.
+	Publish *PublishConfig `json:"publish"`
+}
+
+// PublishConfig controls transitioning a release from draft to published.
+type PublishConfig struct {
+	// Enabled, if true, publishes the release immediately after syncing its
+	// assets, equivalent to passing -publish.
+	Enabled bool `json:"enabled"`
+
+	// Prerelease overrides shipbot's semver-based prerelease detection
+	// (tags with a "-rc.1", "-beta" etc suffix). Unset leaves detection to
+	// the tag name.
+	Prerelease *bool `json:"prerelease"`
+
+	// DiscussionCategoryName, if set, starts a discussion in the named
+	// category when the release is published. GitHub only.
+	DiscussionCategoryName string `json:"discussionCategoryName"`
+
+	// MakeLatest controls whether the release becomes the repository's
+	// "latest" release: "true", "false" or "legacy" (GitHub's own default,
+	// based on creation date). Leaving it unset leaves GitHub's default
+	// behavior in place. GitHub only.
+	MakeLatest string `json:"makeLatest"`
+}
+
+// NotesConfig controls generation of release notes from the commit log,
+// similar to GitHub's own "auto-generate release notes" feature.
+type NotesConfig struct {
+	// PreviousTag pins the tag notes are generated from. If empty, the
+	// most recent tag reachable from the release tag's parent is used.
+	PreviousTag string `json:"previousTag"`
+
+	// ExcludeAuthors lists commit/PR authors (e.g. bot accounts) whose
+	// commits are omitted from the generated notes.
+	ExcludeAuthors []string `json:"excludeAuthors"`
+
+	// Sections groups notes entries, in order, by conventional-commit
+	// subject prefix (e.g. "feat", "fix") or associated PR label. Commits
+	// matching no section are placed in a trailing "Other" section.
+	Sections []NotesSection `json:"sections"`
+
+	// Template is a Go text/template applied to the grouped sections. If
+	// empty, a default template renders each section as a Markdown
+	// heading followed by a bullet per entry.
+	Template string `json:"template"`
+
+	// Mode selects how the generated notes combine with the default
+	// release body: "overwrite" (the default) replaces it; "append" adds
+	// the generated notes after it.
+	Mode string `json:"mode"`
+}
+
+func (c *NotesConfig) mode() string {
+	if c == nil || c.Mode == "" {
+		return "overwrite"
+	}
+	return c.Mode
+}
+
+// NotesSection configures one notes.Section.
+type NotesSection struct {
+	Title    string   `json:"title"`
+	Prefixes []string `json:"prefixes"`
+	Labels   []string `json:"labels"`
+}
+
+func (c *NotesConfig) notesConfig() *notes.Config {
+	sections := make([]notes.Section, 0, len(c.Sections))
+	for _, s := range c.Sections {
+		sections = append(sections, notes.Section{Title: s.Title, Prefixes: s.Prefixes, Labels: s.Labels})
+	}
+	return &notes.Config{
+		PreviousTag:    c.PreviousTag,
+		ExcludeAuthors: c.ExcludeAuthors,
+		Sections:       sections,
+		Template:       c.Template,
+	}
+}
+
+// SignConfig controls signing of release assets, mirroring the signing pipe
+// found in goreleaser and similar release tooling.
+type SignConfig struct {
+	// Cmd is the signing command to invoke. Defaults to "gpg".
+	Cmd string `json:"cmd"`
+
+	// Args are the arguments passed to Cmd. The placeholders {path} and
+	// {signature} are substituted with the path of the artifact being signed
+	// and the path the signature should be written to. If empty, a default
+	// "gpg --detach-sign --armor" invocation is used, configured by KeyID and
+	// PassphraseEnv below.
+	Args []string `json:"args"`
+
+	// KeyID selects the signing key via --local-user. Only used when Args is
+	// not set.
+	KeyID string `json:"keyId"`
+
+	// PassphraseEnv names an environment variable holding the signing key's
+	// passphrase, supplied to gpg over stdin. Only used when Args is not set.
+	PassphraseEnv string `json:"passphraseEnv"`
+
+	// Signature is the suffix appended to the signed file's name to produce
+	// the signature asset's name. Defaults to ".asc".
+	Signature string `json:"signature"`
+
+	// Artifacts selects what gets signed: "all" (every synced asset and the
+	// checksums manifest; the default), "checksum" (only the checksums
+	// manifest), or "none" (disable signing).
+	Artifacts string `json:"artifacts"`
+}
+
+func (c *SignConfig) artifacts() string {
+	if c == nil || c.Artifacts == "" {
+		return "all"
+	}
+	return c.Artifacts
+}
+
+func (c *SignConfig) signsAssets() bool {
+	return c != nil && c.artifacts() == "all"
+}
+
+func (c *SignConfig) signsChecksums() bool {
+	return c != nil && (c.artifacts() == "all" || c.artifacts() == "checksum")
+}
+
+func (c *SignConfig) signature() string {
+	if c != nil && c.Signature != "" {
+		return c.Signature
+	}
+	return ".asc"
+}
+
+// sign invokes the configured (or default gpg) signing command against
+// sourcePath, writing the detached signature to sourcePath+c.signature().
+func (c *SignConfig) sign(sourcePath string) (string, error) {
+	cmdName := c.Cmd
+	if cmdName == "" {
+		cmdName = "gpg"
+	}
+
+	sigPath := sourcePath + c.signature()
+
+	var args []string
+	var stdin io.Reader
+	if len(c.Args) > 0 {
+		for _, a := range c.Args {
+			a = strings.ReplaceAll(a, "{path}", sourcePath)
+			a = strings.ReplaceAll(a, "{signature}", sigPath)
+			args = append(args, a)
+		}
+	} else {
+		args = []string{"--batch", "--yes", "--detach-sign", "--armor", "--output", sigPath}
+		if c.KeyID != "" {
+			args = append(args, "--local-user", c.KeyID)
+		}
+		if c.PassphraseEnv != "" {
+			args = append(args, "--pinentry-mode", "loopback", "--passphrase-fd", "0")
+			stdin = strings.NewReader(os.Getenv(c.PassphraseEnv))
+		}
+		args = append(args, sourcePath)
+	}
+
+	cmd := exec.Command(cmdName, args...)
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("error running %s: %v: %s", cmdName, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return sigPath, nil
+}
+
+// ChecksumsConfig controls generation of an aggregated checksums manifest,
+// similar to the one goreleaser produces alongside release artifacts.
+type ChecksumsConfig struct {
+	// Algorithm is the hash algorithm used both to verify existing assets and
+	// to populate the manifest: "sha256" (the default), "sha512" or "md5".
+	Algorithm string `json:"algorithm"`
+
+	// Filename is the name the manifest is given on the release. Defaults to
+	// "<ALGORITHM>SUMS", e.g. "SHA256SUMS".
+	Filename string `json:"filename"`
+}
+
+func (c *ChecksumsConfig) algorithm() string {
+	if c == nil || c.Algorithm == "" {
+		return "sha256"
+	}
+	return strings.ToLower(c.Algorithm)
+}
+
+func (c *ChecksumsConfig) filename() string {
+	if c != nil && c.Filename != "" {
+		return c.Filename
+	}
+	return strings.ToUpper(c.algorithm()) + "SUMS"
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "md5":
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+	}
+}
+
+// hashFile returns the hex-encoded digest of path, using algorithm.
+func hashFile(path string, algorithm string) (string, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// downloadAssetHash fetches a release asset's content and returns its
+// hex-encoded digest, using algorithm.
+func downloadAssetHash(ctx context.Context, publisher ReleasePublisher, release *Release, asset *Asset, algorithm string) (string, error) {
+	rc, redirectURL, err := publisher.DownloadAsset(ctx, release, asset)
+	if err != nil {
+		return "", fmt.Errorf("error downloading asset: %v", err)
+	}
+	if rc == nil {
+		resp, err := http.Get(redirectURL)
+		if err != nil {
+			return "", fmt.Errorf("error following asset redirect: %v", err)
+		}
+		defer resp.Body.Close()
+		rc = resp.Body
+	} else {
+		defer rc.Close()
+	}
+
+	h, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", fmt.Errorf("error reading asset content: %v", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 type AssetMapping struct {
-	Source     string `json:"source"`
+	// Source is the path to the local file to upload. It may also be a glob
+	// pattern (e.g. "dist/*.tar.gz") or a directory, in which case every
+	// matching file becomes its own upload entry.
+	Source string `json:"source"`
+
+	// GithubName is the name the asset is given on the release. When Source
+	// expands to more than one file, GithubName may contain the template
+	// variables {basename}, {arch} and {os}, which are substituted per
+	// matched file. If empty, it defaults to "{basename}".
 	GithubName string `json:"githubName"`
-	Optional   bool   `json:"optional"`
+
+	// Optional, if true, means it is not an error for Source to match zero
+	// files.
+	Optional bool `json:"optional"`
+
+	// Replace, if true, allows shipbot to delete and re-upload this asset
+	// when its content differs from the existing release asset, instead of
+	// failing. The -force flag enables the same behavior for every asset.
+	Replace bool `json:"replace"`
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "promote" {
+		promoteMain(os.Args[2:])
+		return
+	}
+	releaseMain()
+}
+
+// newShipbot loads configFile and constructs the Shipbot it describes,
+// wiring in a notes.PRLookup and (if dryRun) a dry-run publisher decorator.
+func newShipbot(ctx context.Context, configFile, baseURL, uploadURL string) (*Shipbot, error) {
+	configBytes, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file %q: %v", configFile, err)
+	}
+
+	config := &Config{}
+	if err := yaml.Unmarshal(configBytes, config); err != nil {
+		return nil, fmt.Errorf("error parsing config file %q: %v", configFile, err)
+	}
+
+	publisher, err := newPublisher(ctx, config.Provider, config.Owner, config.Repo, baseURL, uploadURL)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring %s client: %v", config.Provider, err)
+	}
+
+	shipbot := &Shipbot{
+		Publisher: publisher,
+		Config:    config,
+	}
+	if gp, ok := publisher.(*githubPublisher); ok {
+		shipbot.PRLookup = gp.notesPRLookup()
+	}
+
+	if dryRun {
+		shipbot.Publisher = &dryRunPublisher{inner: publisher}
+	}
+
+	return shipbot, nil
+}
+
+func releaseMain() {
 	flag.StringVar(&tag, "tag", "", "tag to push as release")
 	flag.StringVar(&target, "target", "", "commitish value that determines where the tag is created from")
 	flag.StringVar(&configFile, "config", "", "config file to use")
+	flag.BoolVar(&force, "force", false, "allow replacing release assets whose content differs from what is configured locally (also settable per-asset with replace: true)")
+	flag.BoolVar(&prune, "prune", false, "delete release assets that are not listed in the config")
+	flag.BoolVar(&publish, "publish", false, "publish the release once its assets are synced, equivalent to publish: {enabled: true} in the config")
+	flag.BoolVar(&dryRun, "dry-run", false, "log every mutating API call instead of performing it")
+	flag.StringVar(&baseURL, "base-url", "", "base API URL of the git forge, for GitHub Enterprise, Gitea or GitLab (ignored for github.com)")
+	flag.StringVar(&uploadURL, "upload-url", "", "upload API URL, for GitHub Enterprise instances with a separate upload host (defaults to -base-url)")
 	buildDir, err := os.Getwd()
 	if err != nil {
 		glog.Fatalf("error getting current directory: %v", err)
@@ -77,37 +455,9 @@ func main() {
 		glog.Fatalf("must specify -config")
 	}
 
-	configBytes, err := ioutil.ReadFile(configFile)
+	shipbot, err := newShipbot(ctx, configFile, baseURL, uploadURL)
 	if err != nil {
-		glog.Fatalf("error reading config file %q: %v", configFile, err)
-	}
-
-	config := &Config{}
-	if err := yaml.Unmarshal(configBytes, config); err != nil {
-		glog.Fatalf("error parsing config file %q: %v", configFile, err)
-	}
-
-	shipbot := &Shipbot{
-		Config: config,
-	}
-
-	{
-		if githubAccessToken != "" {
-			source := oauth2.StaticTokenSource(&oauth2.Token{
-				AccessToken: githubAccessToken,
-			})
-			shipbot.Client = github.NewClient(oauth2.NewClient(ctx, source))
-
-		} else if githubUser != "" && githubPassword != "" {
-			transport := &github.BasicAuthTransport{
-				Username: githubUser,
-				Password: githubPassword,
-			}
-			shipbot.Client = github.NewClient(transport.Client())
-
-		} else {
-			glog.Fatalf("unable to find github credentials")
-		}
+		glog.Fatalf("%v", err)
 	}
 
 	if err := shipbot.DoRelease(ctx, buildDir); err != nil {
@@ -116,21 +466,26 @@ func main() {
 }
 
 type Shipbot struct {
-	Client *github.Client
-	Config *Config
+	Publisher ReleasePublisher
+	Config    *Config
+
+	// PRLookup resolves the pull request associated with a commit, used to
+	// enrich generated release notes. It is nil unless Config.Notes is set
+	// and Publisher is backed by a GitHub (or GitHub Enterprise) API.
+	PRLookup notes.PRLookup
 }
 
 func (sb *Shipbot) DoRelease(ctx context.Context, buildDir string) error {
-	glog.Infof("listing github releases for %s/%s", sb.Config.Owner, sb.Config.Repo)
-	releases, _, err := sb.Client.Repositories.ListReleases(ctx, sb.Config.Owner, sb.Config.Repo, &github.ListOptions{})
+	glog.Infof("listing releases for %s/%s", sb.Config.Owner, sb.Config.Repo)
+	releases, err := sb.Publisher.ListReleases(ctx)
 	if err != nil {
 		return fmt.Errorf("error listing releases: %v", err)
 	}
 
-	var found *github.RepositoryRelease
+	var found *Release
 	for _, release := range releases {
-		if sv(release.TagName) == tag {
-			glog.Infof("found release: %v", sv(release.TagName))
+		if release.TagName == tag {
+			glog.Infof("found release: %v", release.TagName)
 			found = release
 		}
 	}
@@ -144,40 +499,131 @@ func (sb *Shipbot) DoRelease(ctx context.Context, buildDir string) error {
 		}
 
 		glog.Infof("target commitish: %s", target)
-		release := &github.RepositoryRelease{
-			TagName:         s(tag),
-			TargetCommitish: s(target),
-			Name:            s(tag),
-			Body:            s("Release " + tag + " (draft)"),
-			Draft:           b(true),
+		body := "Release " + tag + " (draft)"
+		if sb.Config.Notes != nil {
+			generated, err := notes.Build(ctx, sb.Config.Notes.notesConfig(), buildDir, tag, sb.PRLookup)
+			if err != nil {
+				return fmt.Errorf("error generating release notes: %v", err)
+			}
+			switch sb.Config.Notes.mode() {
+			case "append":
+				body = strings.TrimSpace(body + "\n\n" + generated)
+			default:
+				if generated != "" {
+					body = generated
+				}
+			}
+		}
+
+		release := &Release{
+			TagName:         tag,
+			TargetCommitish: target,
+			Name:            tag,
+			Body:            body,
+			Draft:           true,
 		}
 
-		glog.Infof("creating github release for %s/%s/%s", sb.Config.Owner, sb.Config.Repo, tag)
-		found, _, err = sb.Client.Repositories.CreateRelease(ctx, sb.Config.Owner, sb.Config.Repo, release)
+		glog.Infof("creating release for %s/%s/%s", sb.Config.Owner, sb.Config.Repo, tag)
+		found, err = sb.Publisher.CreateRelease(ctx, release)
 		if err != nil {
 			return fmt.Errorf("error creating release: %v", err)
 		}
 	}
 
-	glog.Infof("listing github release assets for %s/%s/%s", sb.Config.Owner, sb.Config.Repo, tag)
-	assets, _, err := sb.Client.Repositories.ListReleaseAssets(ctx, sb.Config.Owner, sb.Config.Repo, i64v(found.ID), &github.ListOptions{})
+	glog.Infof("listing release assets for %s/%s/%s", sb.Config.Owner, sb.Config.Repo, tag)
+	assets, err := sb.Publisher.ListAssets(ctx, found)
 	if err != nil {
 		return fmt.Errorf("error listing assets: %v", err)
 	}
 
-	assetMap := make(map[string]*github.ReleaseAsset)
+	assetMap := make(map[string]*Asset)
 	for _, asset := range assets {
-		assetMap[sv(asset.Name)] = asset
+		assetMap[asset.Name] = asset
 	}
 
+	checksums := make(map[string]string)
+	produced := make(map[string]bool)
 	for i := range sb.Config.Assets {
 		assetMapping := &sb.Config.Assets[i]
-		err := sb.syncAsset(ctx, found, assetMapping, assetMap)
+		err := sb.syncAsset(ctx, found, assetMapping, assetMap, checksums, produced)
 		if err != nil {
 			return err
 		}
 	}
 
+	if sb.Config.Checksums != nil {
+		if err := sb.publishChecksums(ctx, buildDir, found, assetMap, checksums, produced); err != nil {
+			return err
+		}
+	}
+
+	if prune {
+		if err := sb.pruneStaleAssets(ctx, found, assetMap, produced); err != nil {
+			return err
+		}
+	}
+
+	if publish || (sb.Config.Publish != nil && sb.Config.Publish.Enabled) {
+		if err := sb.publishRelease(ctx, found); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pruneStaleAssets deletes release assets that are not among the names we
+// just synced (desired).
+func (sb *Shipbot) pruneStaleAssets(ctx context.Context, release *Release, assets map[string]*Asset, desired map[string]bool) error {
+	for name, asset := range assets {
+		if desired[name] {
+			continue
+		}
+
+		glog.Infof("pruning stale release asset %q", name)
+		if err := sb.Publisher.DeleteAsset(ctx, release, asset); err != nil {
+			return fmt.Errorf("error pruning asset %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// publishChecksums writes an aggregated checksums manifest for the assets
+// synced this run to buildDir, then uploads it as a release asset.
+func (sb *Shipbot) publishChecksums(ctx context.Context, buildDir string, release *Release, assets map[string]*Asset, checksums map[string]string, produced map[string]bool) error {
+	if len(checksums) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(checksums))
+	for name := range checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s  %s\n", checksums[name], name)
+	}
+
+	filename := sb.Config.Checksums.filename()
+	path := filepath.Join(buildDir, filename)
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing checksums manifest %q: %v", path, err)
+	}
+
+	glog.Infof("publishing checksums manifest %s", filename)
+	if _, err := sb.syncOneAsset(ctx, release, path, filename, assets, force); err != nil {
+		return fmt.Errorf("error uploading checksums manifest: %v", err)
+	}
+	produced[filename] = true
+
+	if sb.Config.Sign.signsChecksums() {
+		if err := sb.signAndUpload(ctx, release, path, filename, assets, force, produced); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -197,80 +643,197 @@ func findCommitSha(basedir string, tag string) (string, error) {
 	return sha, nil
 }
 
-func (sb *Shipbot) syncAsset(ctx context.Context, release *github.RepositoryRelease, assetMapping *AssetMapping, assets map[string]*github.ReleaseAsset) error {
-	srcStat, err := os.Stat(assetMapping.Source)
+func (sb *Shipbot) syncAsset(ctx context.Context, release *Release, assetMapping *AssetMapping, assets map[string]*Asset, checksums map[string]string, produced map[string]bool) error {
+	matches, err := expandAssetSources(assetMapping.Source)
 	if err != nil {
+		return fmt.Errorf("error expanding source %q: %v", assetMapping.Source, err)
+	}
+
+	if len(matches) == 0 {
 		if !assetMapping.Optional {
-			return fmt.Errorf("error doing stat %q: %v", assetMapping.Source, err)
+			return fmt.Errorf("source %q did not match any files", assetMapping.Source)
 		}
+		glog.Infof("source %q did not match any files; ignoring because it is optional", assetMapping.Source)
+		return nil
+	}
 
-		return nil // ignore not found errors
+	githubNameTemplate := assetMapping.GithubName
+	if githubNameTemplate == "" {
+		githubNameTemplate = "{basename}"
 	}
 
-	existing := assets[assetMapping.GithubName]
-	if existing != nil {
-		// TODO: Fetch asset to see if we can get the SHA (maybe an etag?)
-
-		if int64(iv(existing.Size)) != srcStat.Size() {
-			// TODO: Support force-replace mode?
-			return fmt.Errorf("asset %q size did not match", assetMapping.GithubName)
-		} else {
-			glog.Infof("asset sizes match; assuming the same for %s", assetMapping.GithubName)
-			return nil
+	replaceAllowed := force || assetMapping.Replace
+	for _, match := range matches {
+		githubName, err := renderAssetName(githubNameTemplate, match)
+		if err != nil {
+			return fmt.Errorf("error naming asset for %q: %v", match, err)
+		}
+		assetHash, err := sb.syncOneAsset(ctx, release, match, githubName, assets, replaceAllowed)
+		if err != nil {
+			return err
+		}
+		checksums[githubName] = assetHash
+		produced[githubName] = true
+
+		if sb.Config.Sign.signsAssets() {
+			if err := sb.signAndUpload(ctx, release, match, githubName, assets, replaceAllowed, produced); err != nil {
+				return err
+			}
 		}
 	}
 
-	f, err := os.Open(assetMapping.Source)
+	return nil
+}
+
+// signAndUpload GPG-signs source and uploads the detached signature as a
+// release asset named githubName+sign.signature().
+func (sb *Shipbot) signAndUpload(ctx context.Context, release *Release, source string, githubName string, assets map[string]*Asset, replaceAllowed bool, produced map[string]bool) error {
+	sigPath, err := sb.Config.Sign.sign(source)
 	if err != nil {
-		return fmt.Errorf("error opening %q: %v", assetMapping.Source, err)
+		return fmt.Errorf("error signing %q: %v", source, err)
 	}
-	defer f.Close()
+	defer os.Remove(sigPath)
+
+	sigName := githubName + sb.Config.Sign.signature()
+	if _, err := sb.syncOneAsset(ctx, release, sigPath, sigName, assets, replaceAllowed); err != nil {
+		return fmt.Errorf("error uploading signature for %q: %v", githubName, err)
+	}
+	produced[sigName] = true
+	return nil
+}
 
-	uploadOptions := &github.UploadOptions{
-		Name: assetMapping.GithubName,
+// expandAssetSources resolves a configured source into the list of local
+// files it refers to. source may be a plain path, a glob pattern (as
+// understood by filepath.Glob), or a directory (in which case every regular
+// file directly inside it is included).
+func expandAssetSources(source string) ([]string, error) {
+	if strings.ContainsAny(source, "*?[") {
+		matches, err := filepath.Glob(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %v", source, err)
+		}
+		return matches, nil
 	}
 
-	glog.Infof("creating github release assets for %s/%s/%s %q", sb.Config.Owner, sb.Config.Repo, tag, assetMapping.GithubName)
-	abs, err := filepath.Abs(assetMapping.Source)
+	stat, err := os.Stat(source)
 	if err != nil {
-		glog.V(2).Infof("error getting absolute path for %q: %v", assetMapping.Source, err)
-		abs = assetMapping.Source
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error doing stat %q: %v", source, err)
 	}
-	glog.Infof("uploading %q", abs)
-	asset, _, err := sb.Client.Repositories.UploadReleaseAsset(ctx, sb.Config.Owner, sb.Config.Repo, i64v(release.ID), uploadOptions, f)
+
+	if !stat.IsDir() {
+		return []string{source}, nil
+	}
+
+	entries, err := ioutil.ReadDir(source)
 	if err != nil {
-		return fmt.Errorf("error uploading assets %q: %v", assetMapping.GithubName, err)
+		return nil, fmt.Errorf("error reading directory %q: %v", source, err)
 	}
 
-	glog.Infof("uploaded asset: %v", asset)
-	return nil
+	var matches []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches = append(matches, filepath.Join(source, entry.Name()))
+	}
+	return matches, nil
 }
 
-func sv(v *string) string {
-	if v == nil {
-		return ""
+// renderAssetName substitutes the {basename}, {os} and {arch} template
+// variables in nameTemplate, derived from the matched file's name. It
+// returns an error if nameTemplate references {os} or {arch} but matchedPath
+// contains no recognized GOOS/GOARCH token, rather than uploading an asset
+// whose name still has the literal placeholder in it.
+func renderAssetName(nameTemplate string, matchedPath string) (string, error) {
+	basename := filepath.Base(matchedPath)
+
+	name := nameTemplate
+	name = strings.ReplaceAll(name, "{basename}", basename)
+
+	stem := strings.TrimSuffix(basename, filepath.Ext(basename))
+	for _, part := range strings.FieldsFunc(stem, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+		lower := strings.ToLower(part)
+		if knownGOOS[lower] {
+			name = strings.ReplaceAll(name, "{os}", lower)
+		}
+		if knownGOARCH[lower] {
+			name = strings.ReplaceAll(name, "{arch}", lower)
+		}
 	}
-	return *v
-}
 
-func iv(v *int) int {
-	if v == nil {
-		return 0
+	if strings.Contains(name, "{os}") || strings.Contains(name, "{arch}") {
+		return "", fmt.Errorf("could not resolve {os}/{arch} template variables in %q against filename %q", nameTemplate, basename)
 	}
-	return *v
+
+	return name, nil
 }
 
-func i64v(v *int64) int64 {
-	if v == nil {
-		return 0
+// syncOneAsset uploads a single local file as a release asset (unless an
+// asset of the same name with matching content already exists), returning
+// the hex-encoded digest of its content for use in the checksums manifest.
+func (sb *Shipbot) syncOneAsset(ctx context.Context, release *Release, source string, githubName string, assets map[string]*Asset, replaceAllowed bool) (string, error) {
+	srcStat, err := os.Stat(source)
+	if err != nil {
+		return "", fmt.Errorf("error doing stat %q: %v", source, err)
 	}
-	return *v
-}
 
-func s(v string) *string {
-	return &v
-}
+	algorithm := sb.Config.Checksums.algorithm()
+	localHash, err := hashFile(source, algorithm)
+	if err != nil {
+		return "", fmt.Errorf("error computing %s checksum for %q: %v", algorithm, source, err)
+	}
+
+	existing := assets[githubName]
+	if existing != nil {
+		// existing.Size is 0 for providers that don't report an asset size
+		// (e.g. GitLab release links), in which case we fall through to the
+		// full hash comparison below; otherwise a mismatched size lets us
+		// skip downloading content we already know differs.
+		matches := existing.Size == 0 || existing.Size == srcStat.Size()
+		if matches {
+			remoteHash, err := downloadAssetHash(ctx, sb.Publisher, release, existing, algorithm)
+			if err != nil {
+				return "", fmt.Errorf("error verifying existing asset %q: %v", githubName, err)
+			}
+			matches = remoteHash == localHash
+		}
+
+		if matches {
+			glog.Infof("asset %s checksums match; assuming the same", githubName)
+			return localHash, nil
+		}
+
+		if !replaceAllowed {
+			return "", fmt.Errorf("asset %q content did not match (use -force or replace: true to overwrite)", githubName)
+		}
 
-func b(v bool) *bool {
-	return &v
+		glog.Infof("asset %q content differs from the existing release asset; replacing it", githubName)
+		if err := sb.Publisher.DeleteAsset(ctx, release, existing); err != nil {
+			return "", fmt.Errorf("error deleting existing asset %q: %v", githubName, err)
+		}
+		delete(assets, githubName)
+	}
+
+	f, err := os.Open(source)
+	if err != nil {
+		return "", fmt.Errorf("error opening %q: %v", source, err)
+	}
+	defer f.Close()
+
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		glog.V(2).Infof("error getting absolute path for %q: %v", source, err)
+		abs = source
+	}
+	glog.Infof("uploading %q as %q", abs, githubName)
+	asset, err := sb.Publisher.UploadAsset(ctx, release, githubName, f)
+	if err != nil {
+		return "", fmt.Errorf("error uploading assets %q: %v", githubName, err)
+	}
+
+	glog.Infof("uploaded asset: %v", asset)
+	return localHash, nil
 }
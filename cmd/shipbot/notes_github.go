@@ -0,0 +1,78 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/github"
+
+	"github.com/spotinst/kopeio-shipbot/internal/notes"
+)
+
+// githubPRLookup implements notes.PRLookup against the GitHub API, used to
+// enrich generated release notes with PR title, author and labels.
+type githubPRLookup struct {
+	client *github.Client
+	owner  string
+	repo   string
+}
+
+func (p *githubPublisher) notesPRLookup() notes.PRLookup {
+	return &githubPRLookup{client: p.client, owner: p.owner, repo: p.repo}
+}
+
+// Lookup returns the first pull request associated with sha (GitHub lists
+// the commit's merge PR first), or nil if none is associated with it.
+func (l *githubPRLookup) Lookup(ctx context.Context, sha string) (*notes.PullRequest, error) {
+	u := fmt.Sprintf("repos/%s/%s/commits/%s/pulls", l.owner, l.repo, sha)
+	req, err := l.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The "list pull requests associated with a commit" endpoint requires
+	// this preview media type on the go-github version shipbot is pinned
+	// to.
+	req.Header.Set("Accept", "application/vnd.github.groot-preview+json")
+
+	var prs []*github.PullRequest
+	if _, err := l.client.Do(ctx, req, &prs); err != nil {
+		return nil, fmt.Errorf("error looking up pull requests for commit %s: %v", sha, err)
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+
+	pr := prs[0]
+	var labels []string
+	for _, label := range pr.Labels {
+		labels = append(labels, sv(label.Name))
+	}
+
+	author := ""
+	if pr.User != nil {
+		author = sv(pr.User.Login)
+	}
+
+	return &notes.PullRequest{
+		Number: iv(pr.Number),
+		Title:  sv(pr.Title),
+		Author: author,
+		Labels: labels,
+	}, nil
+}
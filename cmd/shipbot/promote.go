@@ -0,0 +1,124 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+
+	"github.com/golang/glog"
+)
+
+// prereleaseTagPattern matches a semver prerelease suffix, e.g. the
+// "-rc.1" in "v1.2.3-rc.1" or the "-beta" in "1.2.3-beta".
+var prereleaseTagPattern = regexp.MustCompile(`^v?\d+\.\d+\.\d+-`)
+
+// isPrereleaseTag reports whether tag looks like a semver prerelease.
+func isPrereleaseTag(tag string) bool {
+	return prereleaseTagPattern.MatchString(tag)
+}
+
+// publishRelease transitions release from draft to published, detecting
+// prerelease status from its tag's semver suffix unless overridden by
+// sb.Config.Publish.
+func (sb *Shipbot) publishRelease(ctx context.Context, release *Release) error {
+	prerelease := isPrereleaseTag(release.TagName)
+	if sb.Config.Publish != nil && sb.Config.Publish.Prerelease != nil {
+		prerelease = *sb.Config.Publish.Prerelease
+	}
+
+	release.Draft = false
+	release.Prerelease = prerelease
+	if sb.Config.Publish != nil {
+		release.DiscussionCategoryName = sb.Config.Publish.DiscussionCategoryName
+		release.MakeLatest = sb.Config.Publish.MakeLatest
+	}
+
+	glog.Infof("publishing release %q (prerelease=%v)", release.TagName, prerelease)
+	published, err := sb.Publisher.PublishRelease(ctx, release)
+	if err != nil {
+		return fmt.Errorf("error publishing release %q: %v", release.TagName, err)
+	}
+	*release = *published
+	return nil
+}
+
+// promoteMain implements the "promote" subcommand, which transitions an
+// existing draft release to published without syncing any assets.
+func promoteMain(args []string) {
+	// Flags are registered on the global flag.CommandLine (the package-level
+	// flag.StringVar etc. below do this implicitly) rather than a fresh
+	// FlagSet, so that glog's own flags -- logtostderr, -v and friends,
+	// registered on flag.CommandLine by its init() -- are recognized here
+	// too.
+	flag.StringVar(&tag, "tag", "", "tag of the draft release to publish")
+	flag.StringVar(&configFile, "config", "", "config file to use")
+	flag.StringVar(&baseURL, "base-url", "", "base API URL of the git forge, for GitHub Enterprise, Gitea or GitLab (ignored for github.com)")
+	flag.StringVar(&uploadURL, "upload-url", "", "upload API URL, for GitHub Enterprise instances with a separate upload host (defaults to -base-url)")
+	flag.BoolVar(&dryRun, "dry-run", false, "log every mutating API call instead of performing it")
+	prereleaseFlag := flag.String("prerelease", "auto", `whether to mark the release a prerelease: "auto" (detect from the tag's semver suffix, e.g. "-rc.1"), "true" or "false"`)
+	flag.Set("logtostderr", "true")
+	flag.CommandLine.Parse(args)
+
+	ctx := context.Background()
+
+	if tag == "" {
+		glog.Fatalf("must specify -tag")
+	}
+	if configFile == "" {
+		glog.Fatalf("must specify -config")
+	}
+
+	shipbot, err := newShipbot(ctx, configFile, baseURL, uploadURL)
+	if err != nil {
+		glog.Fatalf("%v", err)
+	}
+
+	switch *prereleaseFlag {
+	case "auto":
+		// leave prerelease detection to publishRelease
+	case "true", "false":
+		override := *prereleaseFlag == "true"
+		if shipbot.Config.Publish == nil {
+			shipbot.Config.Publish = &PublishConfig{}
+		}
+		shipbot.Config.Publish.Prerelease = &override
+	default:
+		glog.Fatalf("invalid -prerelease value %q: must be \"auto\", \"true\" or \"false\"", *prereleaseFlag)
+	}
+
+	releases, err := shipbot.Publisher.ListReleases(ctx)
+	if err != nil {
+		glog.Fatalf("error listing releases: %v", err)
+	}
+
+	var found *Release
+	for _, release := range releases {
+		if release.TagName == tag {
+			found = release
+		}
+	}
+	if found == nil {
+		glog.Fatalf("no release found for tag %q", tag)
+	}
+
+	if err := shipbot.publishRelease(ctx, found); err != nil {
+		glog.Fatalf("%v", err)
+	}
+}
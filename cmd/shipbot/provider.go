@@ -0,0 +1,83 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Release is a git-forge-agnostic view of a release.
+type Release struct {
+	ID              int64
+	TagName         string
+	TargetCommitish string
+	Name            string
+	Body            string
+	Draft           bool
+
+	// Prerelease, DiscussionCategoryName and MakeLatest are only consulted
+	// by PublishRelease, and only honored by providers that support them
+	// (currently GitHub; see provider_github.go).
+	Prerelease             bool
+	DiscussionCategoryName string
+	MakeLatest             string
+}
+
+// Asset is a git-forge-agnostic view of a release asset.
+type Asset struct {
+	ID   int64
+	Name string
+	Size int64
+}
+
+// ReleasePublisher is shipbot's view of a git forge's releases API. It is
+// implemented by githubPublisher, giteaPublisher and gitlabPublisher, which
+// let the same config drive publishing to github.com, GitHub Enterprise,
+// self-hosted Gitea, and GitLab (including self-hosted instances).
+type ReleasePublisher interface {
+	ListReleases(ctx context.Context) ([]*Release, error)
+	CreateRelease(ctx context.Context, release *Release) (*Release, error)
+	ListAssets(ctx context.Context, release *Release) ([]*Asset, error)
+	UploadAsset(ctx context.Context, release *Release, name string, file *os.File) (*Asset, error)
+	DeleteAsset(ctx context.Context, release *Release, asset *Asset) error
+	DownloadAsset(ctx context.Context, release *Release, asset *Asset) (rc io.ReadCloser, redirectURL string, err error)
+
+	// PublishRelease transitions release (Draft, Prerelease,
+	// DiscussionCategoryName and MakeLatest as set by the caller) from
+	// draft to published.
+	PublishRelease(ctx context.Context, release *Release) (*Release, error)
+}
+
+// newPublisher constructs the ReleasePublisher selected by provider ("github"
+// the default, "gitea" or "gitlab"). baseURL/uploadURL come from the
+// -base-url/-upload-url flags and are required for Gitea, GitLab and GitHub
+// Enterprise.
+func newPublisher(ctx context.Context, provider, owner, repo, baseURL, uploadURL string) (ReleasePublisher, error) {
+	switch provider {
+	case "", "github":
+		return newGithubPublisher(ctx, owner, repo, baseURL, uploadURL)
+	case "gitea":
+		return newGiteaPublisher(owner, repo, baseURL)
+	case "gitlab":
+		return newGitlabPublisher(owner, repo, baseURL)
+	default:
+		return nil, fmt.Errorf("unknown provider %q", provider)
+	}
+}
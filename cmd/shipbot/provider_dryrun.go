@@ -0,0 +1,70 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/golang/glog"
+)
+
+// dryRunPublisher wraps a ReleasePublisher, logging every mutating call
+// instead of performing it. Read-only calls are passed through to inner so
+// the rest of shipbot still reasons about real existing state.
+type dryRunPublisher struct {
+	inner ReleasePublisher
+}
+
+func (p *dryRunPublisher) ListReleases(ctx context.Context) ([]*Release, error) {
+	return p.inner.ListReleases(ctx)
+}
+
+func (p *dryRunPublisher) CreateRelease(ctx context.Context, release *Release) (*Release, error) {
+	glog.Infof("[dry-run] would create release %q", release.TagName)
+	created := *release
+	created.ID = -1
+	return &created, nil
+}
+
+func (p *dryRunPublisher) ListAssets(ctx context.Context, release *Release) ([]*Asset, error) {
+	if release.ID < 0 {
+		// release is itself a dry-run placeholder that does not exist yet.
+		return nil, nil
+	}
+	return p.inner.ListAssets(ctx, release)
+}
+
+func (p *dryRunPublisher) UploadAsset(ctx context.Context, release *Release, name string, file *os.File) (*Asset, error) {
+	glog.Infof("[dry-run] would upload asset %q to release %q", name, release.TagName)
+	return &Asset{Name: name}, nil
+}
+
+func (p *dryRunPublisher) DeleteAsset(ctx context.Context, release *Release, asset *Asset) error {
+	glog.Infof("[dry-run] would delete asset %q from release %q", asset.Name, release.TagName)
+	return nil
+}
+
+func (p *dryRunPublisher) DownloadAsset(ctx context.Context, release *Release, asset *Asset) (io.ReadCloser, string, error) {
+	return p.inner.DownloadAsset(ctx, release, asset)
+}
+
+func (p *dryRunPublisher) PublishRelease(ctx context.Context, release *Release) (*Release, error) {
+	glog.Infof("[dry-run] would publish release %q (prerelease=%v)", release.TagName, release.Prerelease)
+	return release, nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"code.gitea.io/sdk/gitea"
+)
+
+// giteaPublisher implements ReleasePublisher against a self-hosted Gitea
+// instance.
+type giteaPublisher struct {
+	client *gitea.Client
+	owner  string
+	repo   string
+}
+
+func newGiteaPublisher(owner, repo, baseURL string) (*giteaPublisher, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("must specify -base-url for the gitea provider")
+	}
+
+	var opts []func(*gitea.Client)
+	if githubAccessToken != "" {
+		opts = append(opts, gitea.SetToken(githubAccessToken))
+	} else if githubUser != "" && githubPassword != "" {
+		opts = append(opts, gitea.SetBasicAuth(githubUser, githubPassword))
+	} else {
+		return nil, fmt.Errorf("unable to find gitea credentials")
+	}
+
+	client, err := gitea.NewClient(baseURL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gitea client: %v", err)
+	}
+
+	return &giteaPublisher{client: client, owner: owner, repo: repo}, nil
+}
+
+// authenticate attaches the same credentials used for the Gitea API client
+// to req, needed because attachment content is served from a separate
+// download URL that the gitea SDK does not apply its own auth to.
+func authenticateGiteaRequest(req *http.Request) {
+	if githubAccessToken != "" {
+		req.Header.Set("Authorization", "token "+githubAccessToken)
+	} else if githubUser != "" && githubPassword != "" {
+		req.SetBasicAuth(githubUser, githubPassword)
+	}
+}
+
+func (p *giteaPublisher) ListReleases(ctx context.Context) ([]*Release, error) {
+	releases, _, err := p.client.ListReleases(p.owner, p.repo, gitea.ListReleasesOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Release
+	for _, r := range releases {
+		result = append(result, giteaToRelease(r))
+	}
+	return result, nil
+}
+
+func (p *giteaPublisher) CreateRelease(ctx context.Context, release *Release) (*Release, error) {
+	created, _, err := p.client.CreateRelease(p.owner, p.repo, gitea.CreateReleaseOption{
+		TagName: release.TagName,
+		Target:  release.TargetCommitish,
+		Title:   release.Name,
+		Note:    release.Body,
+		IsDraft: release.Draft,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return giteaToRelease(created), nil
+}
+
+func (p *giteaPublisher) ListAssets(ctx context.Context, release *Release) ([]*Asset, error) {
+	attachments, _, err := p.client.ListReleaseAttachments(p.owner, p.repo, release.ID, gitea.ListReleaseAttachmentsOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Asset
+	for _, a := range attachments {
+		result = append(result, &Asset{ID: a.ID, Name: a.Name, Size: a.Size})
+	}
+	return result, nil
+}
+
+func (p *giteaPublisher) UploadAsset(ctx context.Context, release *Release, name string, file *os.File) (*Asset, error) {
+	attachment, _, err := p.client.CreateReleaseAttachment(p.owner, p.repo, release.ID, file, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Asset{ID: attachment.ID, Name: attachment.Name, Size: attachment.Size}, nil
+}
+
+func (p *giteaPublisher) DeleteAsset(ctx context.Context, release *Release, asset *Asset) error {
+	_, err := p.client.DeleteReleaseAttachment(p.owner, p.repo, release.ID, asset.ID)
+	return err
+}
+
+func (p *giteaPublisher) DownloadAsset(ctx context.Context, release *Release, asset *Asset) (io.ReadCloser, string, error) {
+	attachment, _, err := p.client.GetReleaseAttachment(p.owner, p.repo, release.ID, asset.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Gitea serves attachment content from a separate download URL rather
+	// than the API endpoint, which (unlike GitHub's presigned asset
+	// redirects) requires the same credentials as the API itself, so we
+	// fetch it ourselves instead of handing the bare URL back to the
+	// caller.
+	req, err := http.NewRequestWithContext(ctx, "GET", attachment.DownloadURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	authenticateGiteaRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading attachment %q: %v", asset.Name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("error downloading attachment %q: unexpected status %s", asset.Name, resp.Status)
+	}
+	return resp.Body, "", nil
+}
+
+// PublishRelease flips release to published, setting its prerelease status.
+// Gitea has no notion of a discussion category or a "latest" pointer, so
+// release.DiscussionCategoryName/MakeLatest are rejected rather than
+// silently dropped.
+func (p *giteaPublisher) PublishRelease(ctx context.Context, release *Release) (*Release, error) {
+	if release.DiscussionCategoryName != "" || release.MakeLatest != "" {
+		return nil, fmt.Errorf("discussionCategoryName and makeLatest are not supported by the gitea provider")
+	}
+
+	updated, _, err := p.client.EditRelease(p.owner, p.repo, release.ID, gitea.EditReleaseOption{
+		TagName:      release.TagName,
+		Target:       release.TargetCommitish,
+		Title:        release.Name,
+		Note:         release.Body,
+		IsDraft:      &release.Draft,
+		IsPrerelease: &release.Prerelease,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return giteaToRelease(updated), nil
+}
+
+func giteaToRelease(r *gitea.Release) *Release {
+	return &Release{
+		ID:              r.ID,
+		TagName:         r.TagName,
+		TargetCommitish: r.Target,
+		Name:            r.Title,
+		Body:            r.Note,
+		Draft:           r.IsDraft,
+		Prerelease:      r.IsPrerelease,
+	}
+}
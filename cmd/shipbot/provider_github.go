@@ -0,0 +1,208 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+)
+
+// githubPublisher implements ReleasePublisher against github.com, or a
+// GitHub Enterprise instance when baseURL is set.
+type githubPublisher struct {
+	client *github.Client
+	owner  string
+	repo   string
+}
+
+func newGithubPublisher(ctx context.Context, owner, repo, baseURL, uploadURL string) (*githubPublisher, error) {
+	var httpClient *http.Client
+	if githubAccessToken != "" {
+		source := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: githubAccessToken})
+		httpClient = oauth2.NewClient(ctx, source)
+	} else if githubUser != "" && githubPassword != "" {
+		transport := &github.BasicAuthTransport{
+			Username: githubUser,
+			Password: githubPassword,
+		}
+		httpClient = transport.Client()
+	} else {
+		return nil, fmt.Errorf("unable to find github credentials")
+	}
+
+	var client *github.Client
+	if baseURL != "" {
+		if uploadURL == "" {
+			uploadURL = baseURL
+		}
+		var err error
+		client, err = github.NewEnterpriseClient(baseURL, uploadURL, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("error creating github enterprise client: %v", err)
+		}
+	} else {
+		client = github.NewClient(httpClient)
+	}
+
+	return &githubPublisher{client: client, owner: owner, repo: repo}, nil
+}
+
+func (p *githubPublisher) ListReleases(ctx context.Context) ([]*Release, error) {
+	releases, _, err := p.client.Repositories.ListReleases(ctx, p.owner, p.repo, &github.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Release
+	for _, r := range releases {
+		result = append(result, githubToRelease(r))
+	}
+	return result, nil
+}
+
+func (p *githubPublisher) CreateRelease(ctx context.Context, release *Release) (*Release, error) {
+	created, _, err := p.client.Repositories.CreateRelease(ctx, p.owner, p.repo, &github.RepositoryRelease{
+		TagName:         s(release.TagName),
+		TargetCommitish: s(release.TargetCommitish),
+		Name:            s(release.Name),
+		Body:            s(release.Body),
+		Draft:           b(release.Draft),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return githubToRelease(created), nil
+}
+
+func (p *githubPublisher) ListAssets(ctx context.Context, release *Release) ([]*Asset, error) {
+	assets, _, err := p.client.Repositories.ListReleaseAssets(ctx, p.owner, p.repo, release.ID, &github.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Asset
+	for _, a := range assets {
+		result = append(result, &Asset{ID: i64v(a.ID), Name: sv(a.Name), Size: int64(iv(a.Size))})
+	}
+	return result, nil
+}
+
+func (p *githubPublisher) UploadAsset(ctx context.Context, release *Release, name string, file *os.File) (*Asset, error) {
+	asset, _, err := p.client.Repositories.UploadReleaseAsset(ctx, p.owner, p.repo, release.ID, &github.UploadOptions{Name: name}, file)
+	if err != nil {
+		return nil, err
+	}
+	return &Asset{ID: i64v(asset.ID), Name: sv(asset.Name), Size: int64(iv(asset.Size))}, nil
+}
+
+func (p *githubPublisher) DeleteAsset(ctx context.Context, release *Release, asset *Asset) error {
+	_, err := p.client.Repositories.DeleteReleaseAsset(ctx, p.owner, p.repo, asset.ID)
+	return err
+}
+
+func (p *githubPublisher) DownloadAsset(ctx context.Context, release *Release, asset *Asset) (io.ReadCloser, string, error) {
+	return p.client.Repositories.DownloadReleaseAsset(ctx, p.owner, p.repo, asset.ID)
+}
+
+func sv(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+func iv(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func i64v(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func bv(v *bool) bool {
+	if v == nil {
+		return false
+	}
+	return *v
+}
+
+func s(v string) *string {
+	return &v
+}
+
+func b(v bool) *bool {
+	return &v
+}
+
+// PublishRelease flips release to published, setting prerelease status and,
+// when set, the discussion category and "latest" pointer. DiscussionCategoryName
+// and MakeLatest are not modeled by the go-github RepositoryRelease type this
+// client is pinned to, so they are sent via a raw request instead of
+// p.client.Repositories.EditRelease.
+func (p *githubPublisher) PublishRelease(ctx context.Context, release *Release) (*Release, error) {
+	body := struct {
+		Draft                  bool    `json:"draft"`
+		Prerelease             bool    `json:"prerelease"`
+		DiscussionCategoryName *string `json:"discussion_category_name,omitempty"`
+		MakeLatest             *string `json:"make_latest,omitempty"`
+	}{
+		Draft:      release.Draft,
+		Prerelease: release.Prerelease,
+	}
+	if release.DiscussionCategoryName != "" {
+		body.DiscussionCategoryName = s(release.DiscussionCategoryName)
+	}
+	if release.MakeLatest != "" {
+		body.MakeLatest = s(release.MakeLatest)
+	}
+
+	u := fmt.Sprintf("repos/%s/%s/releases/%d", p.owner, p.repo, release.ID)
+	req, err := p.client.NewRequest("PATCH", u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated github.RepositoryRelease
+	if _, err := p.client.Do(ctx, req, &updated); err != nil {
+		return nil, fmt.Errorf("error publishing release: %v", err)
+	}
+	return githubToRelease(&updated), nil
+}
+
+func githubToRelease(r *github.RepositoryRelease) *Release {
+	return &Release{
+		ID:              i64v(r.ID),
+		TagName:         sv(r.TagName),
+		TargetCommitish: sv(r.TargetCommitish),
+		Name:            sv(r.Name),
+		Body:            sv(r.Body),
+		Draft:           bv(r.Draft),
+		Prerelease:      bv(r.Prerelease),
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// gitlabPublisher implements ReleasePublisher against gitlab.com, or a
+// self-hosted GitLab instance when baseURL is set. GitLab has no notion of a
+// binary release asset: a file is uploaded to the project, and a release
+// "link" is created pointing at it.
+type gitlabPublisher struct {
+	client  *gitlab.Client
+	project string
+	webURL  string
+}
+
+func newGitlabPublisher(owner, repo, baseURL string) (*gitlabPublisher, error) {
+	var opts []gitlab.ClientOptionFunc
+	if baseURL != "" {
+		opts = append(opts, gitlab.WithBaseURL(baseURL))
+	}
+
+	var client *gitlab.Client
+	var err error
+	if githubAccessToken != "" {
+		client, err = gitlab.NewClient(githubAccessToken, opts...)
+	} else if githubUser != "" && githubPassword != "" {
+		client, err = gitlab.NewBasicAuthClient(githubUser, githubPassword, opts...)
+	} else {
+		return nil, fmt.Errorf("unable to find gitlab credentials")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating gitlab client: %v", err)
+	}
+
+	project := owner + "/" + repo
+	p, _, err := client.Projects.GetProject(project, &gitlab.GetProjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error looking up gitlab project %q: %v", project, err)
+	}
+
+	return &gitlabPublisher{client: client, project: project, webURL: p.WebURL}, nil
+}
+
+func (p *gitlabPublisher) ListReleases(ctx context.Context) ([]*Release, error) {
+	releases, _, err := p.client.Releases.ListReleases(p.project, &gitlab.ListReleasesOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Release
+	for _, r := range releases {
+		result = append(result, gitlabToRelease(r))
+	}
+	return result, nil
+}
+
+func (p *gitlabPublisher) CreateRelease(ctx context.Context, release *Release) (*Release, error) {
+	created, _, err := p.client.Releases.CreateRelease(p.project, &gitlab.CreateReleaseOptions{
+		TagName:     gitlab.String(release.TagName),
+		Ref:         gitlab.String(release.TargetCommitish),
+		Name:        gitlab.String(release.Name),
+		Description: gitlab.String(release.Body),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gitlabToRelease(created), nil
+}
+
+func (p *gitlabPublisher) ListAssets(ctx context.Context, release *Release) ([]*Asset, error) {
+	links, _, err := p.client.ReleaseLinks.ListReleaseLinks(p.project, release.TagName, &gitlab.ListReleaseLinksOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Asset
+	for _, l := range links {
+		result = append(result, &Asset{ID: int64(l.ID), Name: l.Name})
+	}
+	return result, nil
+}
+
+func (p *gitlabPublisher) UploadAsset(ctx context.Context, release *Release, name string, file *os.File) (*Asset, error) {
+	uploaded, _, err := p.client.Projects.UploadFile(p.project, file, name)
+	if err != nil {
+		return nil, fmt.Errorf("error uploading file to project: %v", err)
+	}
+
+	link, _, err := p.client.ReleaseLinks.CreateReleaseLink(p.project, release.TagName, &gitlab.CreateReleaseLinkOptions{
+		Name: gitlab.String(name),
+		URL:  gitlab.String(p.webURL + uploaded.URL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating release link: %v", err)
+	}
+
+	return &Asset{ID: int64(link.ID), Name: link.Name}, nil
+}
+
+func (p *gitlabPublisher) DeleteAsset(ctx context.Context, release *Release, asset *Asset) error {
+	_, _, err := p.client.ReleaseLinks.DeleteReleaseLink(p.project, release.TagName, int(asset.ID))
+	return err
+}
+
+func (p *gitlabPublisher) DownloadAsset(ctx context.Context, release *Release, asset *Asset) (io.ReadCloser, string, error) {
+	link, _, err := p.client.ReleaseLinks.GetReleaseLink(p.project, release.TagName, int(asset.ID))
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Unlike GitHub's presigned asset redirects, a release link's direct
+	// asset URL requires the same credentials as the API itself, so we
+	// fetch it ourselves instead of handing the bare URL back to the
+	// caller.
+	req, err := http.NewRequestWithContext(ctx, "GET", link.DirectAssetURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if githubAccessToken != "" {
+		req.Header.Set("PRIVATE-TOKEN", githubAccessToken)
+	} else if githubUser != "" && githubPassword != "" {
+		req.SetBasicAuth(githubUser, githubPassword)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading asset %q: %v", asset.Name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("error downloading asset %q: unexpected status %s", asset.Name, resp.Status)
+	}
+	return resp.Body, "", nil
+}
+
+// PublishRelease is a no-op: GitLab releases have no draft state, prerelease
+// flag, discussion category or "latest" pointer, so a release is already
+// "published" as soon as it is created.
+func (p *gitlabPublisher) PublishRelease(ctx context.Context, release *Release) (*Release, error) {
+	return release, nil
+}
+
+func gitlabToRelease(r *gitlab.Release) *Release {
+	return &Release{
+		TagName: r.TagName,
+		Name:    r.Name,
+		Body:    r.Description,
+	}
+}
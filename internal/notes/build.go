@@ -0,0 +1,147 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Config controls how release notes are generated.
+type Config struct {
+	// PreviousTag pins the tag notes are generated from. If empty, the
+	// most recent tag reachable from tag's parent is used, and an empty
+	// result means tag has no ancestor tag (e.g. it is the first release).
+	PreviousTag string
+
+	// ExcludeAuthors lists commit/PR authors (e.g. bot accounts) whose
+	// commits are omitted from the generated notes.
+	ExcludeAuthors []string
+
+	// Sections groups entries, in order; see Section and Group.
+	Sections []Section
+
+	// Template is a Go text/template applied to the grouped sections. See
+	// Render.
+	Template string
+}
+
+// Build generates release notes for the commits reachable from tag but not
+// from cfg.PreviousTag (or the nearest preceding tag, if unset) in
+// buildDir's git history, enriching each with pull request metadata from
+// lookup, which may be nil to skip enrichment.
+func Build(ctx context.Context, cfg *Config, buildDir, tag string, lookup PRLookup) (string, error) {
+	previousTag := cfg.PreviousTag
+	if previousTag == "" {
+		var err error
+		previousTag, err = previousTagOf(buildDir, tag)
+		if err != nil {
+			return "", fmt.Errorf("error finding previous tag: %v", err)
+		}
+	}
+
+	commits, err := commitLog(buildDir, previousTag, tag)
+	if err != nil {
+		return "", fmt.Errorf("error reading commit log: %v", err)
+	}
+
+	excluded := make(map[string]bool, len(cfg.ExcludeAuthors))
+	for _, author := range cfg.ExcludeAuthors {
+		excluded[author] = true
+	}
+
+	var entries []Entry
+	for _, c := range commits {
+		if excluded[c.Author] {
+			continue
+		}
+
+		entry := Entry{Commit: c}
+		if lookup != nil {
+			pr, err := lookup.Lookup(ctx, c.SHA)
+			if err != nil {
+				return "", fmt.Errorf("error looking up pull request for %s: %v", c.SHA, err)
+			}
+			entry.PR = pr
+		}
+		if entry.PR != nil && excluded[entry.PR.Author] {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return Render(cfg.Template, Group(entries, cfg.Sections))
+}
+
+// previousTagOf returns the most recent tag reachable from tag's parent, or
+// "" if tag has no ancestor tag.
+func previousTagOf(buildDir, tag string) (string, error) {
+	cmd := exec.Command("git", "describe", "--tags", "--abbrev=0", tag+"^")
+	cmd.Dir = buildDir
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(stderr.String(), "No tags can describe") || strings.Contains(stderr.String(), "No names found") {
+			return "", nil
+		}
+		return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// logFieldSep separates the fields of each commitLog entry. It is chosen to
+// never appear in a commit subject or author name.
+const logFieldSep = "\x1f"
+
+// commitLog returns the commits reachable from tag but not from
+// previousTag (or all ancestors of tag, if previousTag is ""), oldest
+// first.
+func commitLog(buildDir, previousTag, tag string) ([]Commit, error) {
+	commitRange := tag
+	if previousTag != "" {
+		commitRange = previousTag + ".." + tag
+	}
+
+	cmd := exec.Command("git", "log", "--reverse", "--pretty=format:%H"+logFieldSep+"%s"+logFieldSep+"%an", commitRange)
+	cmd.Dir = buildDir
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	trimmed := strings.TrimSpace(out.String())
+	if trimmed == "" {
+		return nil, nil
+	}
+
+	var commits []Commit
+	for _, line := range strings.Split(trimmed, "\n") {
+		fields := strings.Split(line, logFieldSep)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git log output: %q", line)
+		}
+		commits = append(commits, Commit{SHA: fields[0], Subject: fields[1], Author: fields[2]})
+	}
+	return commits, nil
+}
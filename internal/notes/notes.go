@@ -0,0 +1,192 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notes generates release notes from the commit log between two
+// tags, optionally enriched with associated GitHub pull request metadata.
+package notes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Commit is a single entry from `git log`.
+type Commit struct {
+	SHA     string
+	Subject string
+	Author  string
+}
+
+// PullRequest is the subset of a GitHub pull request's metadata used to
+// enrich and group a commit's notes entry.
+type PullRequest struct {
+	Number int
+	Title  string
+	Author string
+	Labels []string
+}
+
+// PRLookup resolves the pull request (if any) associated with a commit. It
+// is implemented against the GitHub API in cmd/shipbot; tests use a fake.
+type PRLookup interface {
+	Lookup(ctx context.Context, sha string) (*PullRequest, error)
+}
+
+// Entry is a single release notes line: a commit, optionally enriched with
+// the pull request that introduced it.
+type Entry struct {
+	Commit
+	PR *PullRequest
+}
+
+// Title returns the associated pull request's title, if there is one and it
+// is non-empty, else the commit subject.
+func (e Entry) Title() string {
+	if e.PR != nil && e.PR.Title != "" {
+		return e.PR.Title
+	}
+	return e.Subject
+}
+
+// Section groups entries under a Markdown heading, matched either by
+// conventional-commit subject prefix (e.g. "feat", "fix") or by associated
+// pull request label.
+type Section struct {
+	Title    string
+	Prefixes []string
+	Labels   []string
+}
+
+func (s Section) matches(e Entry) bool {
+	prefix := conventionalPrefix(e.Subject)
+	for _, p := range s.Prefixes {
+		if prefix == p {
+			return true
+		}
+	}
+
+	if e.PR == nil {
+		return false
+	}
+	for _, label := range s.Labels {
+		for _, prLabel := range e.PR.Labels {
+			if prLabel == label {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// otherTitle is the heading used for entries that match no configured
+// section.
+const otherTitle = "Other"
+
+// GroupedSection is a Section populated with the entries that matched it.
+type GroupedSection struct {
+	Title   string
+	Entries []Entry
+}
+
+// Group buckets entries into sections, preserving the order sections is
+// given. Entries matching no section are collected last, under
+// otherTitle. Sections (including Other) with no entries are omitted.
+func Group(entries []Entry, sections []Section) []GroupedSection {
+	grouped := make([]GroupedSection, len(sections))
+	for i, s := range sections {
+		grouped[i].Title = s.Title
+	}
+	other := GroupedSection{Title: otherTitle}
+
+	for _, e := range entries {
+		placed := false
+		for i, s := range sections {
+			if s.matches(e) {
+				grouped[i].Entries = append(grouped[i].Entries, e)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			other.Entries = append(other.Entries, e)
+		}
+	}
+
+	var result []GroupedSection
+	for _, g := range grouped {
+		if len(g.Entries) > 0 {
+			result = append(result, g)
+		}
+	}
+	if len(other.Entries) > 0 {
+		result = append(result, other)
+	}
+	return result
+}
+
+// conventionalPrefix returns the conventional-commit type of subject (e.g.
+// "feat" from "feat(api): add widget", or "feat!" from "feat!: breaking
+// change"), or "" if subject does not follow the convention.
+func conventionalPrefix(subject string) string {
+	colon := strings.IndexByte(subject, ':')
+	if colon <= 0 {
+		return ""
+	}
+
+	prefix := subject[:colon]
+	if paren := strings.IndexByte(prefix, '('); paren >= 0 {
+		prefix = prefix[:paren]
+	}
+
+	for _, r := range prefix {
+		if !unicode.IsLetter(r) && r != '!' {
+			return ""
+		}
+	}
+	return prefix
+}
+
+// defaultTemplate renders each section as a Markdown heading followed by a
+// bullet per entry, linking PR numbers where known.
+const defaultTemplate = `{{- range .}}
+## {{.Title}}
+{{range .Entries}}- {{.Title}}{{if .PR}} (#{{.PR.Number}}){{end}}
+{{end}}
+{{- end}}`
+
+// Render applies tmpl (or defaultTemplate, if empty) to the grouped
+// sections.
+func Render(tmpl string, sections []GroupedSection) (string, error) {
+	if tmpl == "" {
+		tmpl = defaultTemplate
+	}
+
+	t, err := template.New("notes").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("error parsing notes template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, sections); err != nil {
+		return "", fmt.Errorf("error rendering notes template: %v", err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notes
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	sections := []Section{
+		{Title: "Features", Prefixes: []string{"feat"}},
+		{Title: "Fixes", Prefixes: []string{"fix"}, Labels: []string{"bug"}},
+	}
+
+	entries := []Entry{
+		{Commit: Commit{SHA: "1", Subject: "feat: add widget"}},
+		{Commit: Commit{SHA: "2", Subject: "fix: crash on startup"}},
+		{Commit: Commit{SHA: "3", Subject: "tidy up docs"}},
+		{Commit: Commit{SHA: "4", Subject: "improve latency"}, PR: &PullRequest{Title: "Improve latency", Labels: []string{"bug"}}},
+	}
+
+	grouped := Group(entries, sections)
+	if len(grouped) != 3 {
+		t.Fatalf("expected 3 non-empty sections, got %d: %+v", len(grouped), grouped)
+	}
+
+	if grouped[0].Title != "Features" || len(grouped[0].Entries) != 1 {
+		t.Errorf("unexpected Features section: %+v", grouped[0])
+	}
+	if grouped[1].Title != "Fixes" || len(grouped[1].Entries) != 2 {
+		t.Errorf("unexpected Fixes section: %+v", grouped[1])
+	}
+	if grouped[2].Title != otherTitle || len(grouped[2].Entries) != 1 {
+		t.Errorf("unexpected Other section: %+v", grouped[2])
+	}
+}
+
+func TestConventionalPrefix(t *testing.T) {
+	cases := map[string]string{
+		"feat: add widget":        "feat",
+		"feat(api): add widget":   "feat",
+		"feat!: breaking change":  "feat!",
+		"tidy up docs":            "",
+		"Merge pull request #123": "",
+	}
+	for subject, want := range cases {
+		if got := conventionalPrefix(subject); got != want {
+			t.Errorf("conventionalPrefix(%q) = %q, want %q", subject, got, want)
+		}
+	}
+}
+
+func TestRenderDefaultTemplate(t *testing.T) {
+	grouped := Group([]Entry{
+		{Commit: Commit{SHA: "abcdef0123456", Subject: "feat: add widget"}},
+	}, []Section{{Title: "Features", Prefixes: []string{"feat"}}})
+
+	out, err := Render("", grouped)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out, "## Features") || !strings.Contains(out, "feat: add widget") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestRenderCustomTemplate(t *testing.T) {
+	tmpl, err := ioutil.ReadFile(filepath.Join("testdata", "notes.tmpl"))
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+
+	grouped := Group([]Entry{
+		{Commit: Commit{SHA: "1", Subject: "fix: crash on startup"}},
+	}, []Section{{Title: "Fixes", Prefixes: []string{"fix"}}})
+
+	out, err := Render(string(tmpl), grouped)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out, "Fixes:") || !strings.Contains(out, "* fix: crash on startup") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+// testRepo creates a throwaway git repository with two tagged commits and
+// one untagged commit, returning its directory.
+func testRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(cmd.Env,
+			"GIT_AUTHOR_NAME=tester", "GIT_AUTHOR_EMAIL=tester@example.com",
+			"GIT_COMMITTER_NAME=tester", "GIT_COMMITTER_EMAIL=tester@example.com",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	run("init", "-q")
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "a.txt")
+	run("commit", "-q", "-m", "feat: initial commit")
+	run("tag", "v1.0.0")
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "b.txt")
+	run("commit", "-q", "-m", "fix: second commit")
+	run("tag", "v1.1.0")
+
+	return dir
+}
+
+type fakeLookup struct{}
+
+func (fakeLookup) Lookup(ctx context.Context, sha string) (*PullRequest, error) {
+	return nil, nil
+}
+
+func TestBuild(t *testing.T) {
+	dir := testRepo(t)
+
+	cfg := &Config{
+		Sections: []Section{
+			{Title: "Features", Prefixes: []string{"feat"}},
+			{Title: "Fixes", Prefixes: []string{"fix"}},
+		},
+	}
+
+	out, err := Build(context.Background(), cfg, dir, "v1.1.0", fakeLookup{})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	if strings.Contains(out, "initial commit") {
+		t.Errorf("expected notes to exclude commits before the previous tag, got:\n%s", out)
+	}
+	if !strings.Contains(out, "fix: second commit") {
+		t.Errorf("expected notes to include the commit since the previous tag, got:\n%s", out)
+	}
+}
+
+func TestBuildExcludesAuthor(t *testing.T) {
+	dir := testRepo(t)
+
+	cfg := &Config{
+		PreviousTag:    "",
+		ExcludeAuthors: []string{"tester"},
+	}
+
+	out, err := Build(context.Background(), cfg, dir, "v1.1.0", nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if out != "" {
+		t.Errorf("expected no notes once the only author is excluded, got:\n%s", out)
+	}
+}